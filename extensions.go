@@ -0,0 +1,149 @@
+package gositemap
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Image is the Google image sitemap extension:
+// https://developers.google.com/search/docs/crawling-indexing/sitemaps/image-sitemaps
+type Image struct {
+	Loc string `xml:"http://www.google.com/schemas/sitemap-image/1.1 loc"`
+}
+
+// Video is the Google video sitemap extension:
+// https://developers.google.com/search/docs/crawling-indexing/sitemaps/video-sitemaps
+type Video struct {
+	Title           string      `xml:"http://www.google.com/schemas/sitemap-video/1.1 title"`
+	Description     string      `xml:"http://www.google.com/schemas/sitemap-video/1.1 description"`
+	ThumbnailLoc    string      `xml:"http://www.google.com/schemas/sitemap-video/1.1 thumbnail_loc"`
+	Duration        int         `xml:"http://www.google.com/schemas/sitemap-video/1.1 duration"`
+	PublicationDate TimeISO3339 `xml:"http://www.google.com/schemas/sitemap-video/1.1 publication_date"`
+}
+
+// News is the Google News sitemap extension:
+// https://developers.google.com/search/docs/crawling-indexing/sitemaps/news-sitemap
+type News struct {
+	PublicationName     string      `xml:"http://www.google.com/schemas/sitemap-news/0.9 publication>name"`
+	PublicationLanguage string      `xml:"http://www.google.com/schemas/sitemap-news/0.9 publication>language"`
+	PublicationDate     TimeISO3339 `xml:"http://www.google.com/schemas/sitemap-news/0.9 publication_date"`
+	Title               string      `xml:"http://www.google.com/schemas/sitemap-news/0.9 title"`
+}
+
+// Alternate is an xhtml:link rel="alternate" annotation used to point at
+// translated versions of a URL:
+// https://developers.google.com/search/docs/specialty/international/localized-versions#sitemap
+type Alternate struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+func init() {
+	registerElement(url, "image:image", &elementDef{
+		level:     image,
+		container: true,
+		onOpen:    func(p *urlParser) { p.image = &Image{} },
+		onPop:     func(p *urlParser) error { p.url.Images = append(p.url.Images, *p.image); return nil },
+	})
+	registerElement(image, "image:loc", &elementDef{
+		level:   imageLoc,
+		onClose: func(p *urlParser, text string) error { p.image.Loc = text; return nil },
+	})
+
+	registerElement(url, "video:video", &elementDef{
+		level:     video,
+		container: true,
+		onOpen:    func(p *urlParser) { p.video = &Video{} },
+		onPop:     func(p *urlParser) error { p.url.Videos = append(p.url.Videos, *p.video); return nil },
+	})
+	registerElement(video, "video:title", &elementDef{
+		level:   videoTitle,
+		onClose: func(p *urlParser, text string) error { p.video.Title = text; return nil },
+	})
+	registerElement(video, "video:description", &elementDef{
+		level:   videoDescription,
+		onClose: func(p *urlParser, text string) error { p.video.Description = text; return nil },
+	})
+	registerElement(video, "video:thumbnail_loc", &elementDef{
+		level:   videoThumbnailLoc,
+		onClose: func(p *urlParser, text string) error { p.video.ThumbnailLoc = text; return nil },
+	})
+	registerElement(video, "video:duration", &elementDef{
+		level: videoDuration,
+		onClose: func(p *urlParser, text string) error {
+			d, err := strconv.Atoi(text)
+			if err != nil {
+				return fmt.Errorf("invalid video:duration %q: %w", text, err)
+			}
+			p.video.Duration = d
+			return nil
+		},
+	})
+	registerElement(video, "video:publication_date", &elementDef{
+		level: videoPublicationDate,
+		onClose: func(p *urlParser, text string) error {
+			tt, skip, err := resolveLastmod(text, p.policy)
+			if err != nil {
+				return fmt.Errorf("invalid video:publication_date %q: %w", text, err)
+			}
+			if skip {
+				p.skip = true
+				return nil
+			}
+			p.video.PublicationDate.Time = tt
+			return nil
+		},
+	})
+
+	registerElement(url, "news:news", &elementDef{
+		level:     news,
+		container: true,
+		onOpen:    func(p *urlParser) { p.news = &News{} },
+		onPop:     func(p *urlParser) error { p.url.News = p.news; return nil },
+	})
+	registerElement(news, "news:publication", &elementDef{
+		level:     newsPublication,
+		container: true,
+	})
+	registerElement(newsPublication, "news:name", &elementDef{
+		level:   newsPublicationName,
+		onClose: func(p *urlParser, text string) error { p.news.PublicationName = text; return nil },
+	})
+	registerElement(newsPublication, "news:language", &elementDef{
+		level:   newsPublicationLanguage,
+		onClose: func(p *urlParser, text string) error { p.news.PublicationLanguage = text; return nil },
+	})
+	registerElement(news, "news:publication_date", &elementDef{
+		level: newsPublicationDate,
+		onClose: func(p *urlParser, text string) error {
+			tt, skip, err := resolveLastmod(text, p.policy)
+			if err != nil {
+				return fmt.Errorf("invalid news:publication_date %q: %w", text, err)
+			}
+			if skip {
+				p.skip = true
+				return nil
+			}
+			p.news.PublicationDate.Time = tt
+			return nil
+		},
+	})
+	registerElement(news, "news:title", &elementDef{
+		level:   newsTitle,
+		onClose: func(p *urlParser, text string) error { p.news.Title = text; return nil },
+	})
+
+	registerElement(url, "xhtml:link", &elementDef{
+		level: xhtmlLink,
+		onAttrs: func(p *urlParser, attrs string) {
+			if attrValue(attrs, "rel") != "alternate" {
+				return
+			}
+			p.url.Alternates = append(p.url.Alternates, Alternate{
+				Hreflang: attrValue(attrs, "hreflang"),
+				Href:     attrValue(attrs, "href"),
+			})
+		},
+	})
+}
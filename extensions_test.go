@@ -0,0 +1,106 @@
+package gositemap_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gositemap "github.com/tmeire/go-sitemap"
+)
+
+const extendedURLSet = `<urlset
+	xmlns:image="http://www.google.com/schemas/sitemap-image/1.1"
+	xmlns:video="http://www.google.com/schemas/sitemap-video/1.1"
+	xmlns:news="http://www.google.com/schemas/sitemap-news/0.9"
+	xmlns:xhtml="http://www.w3.org/1999/xhtml">
+<url>
+	<loc>https://example.com/a</loc>
+	<image:image><image:loc>https://example.com/a.jpg</image:loc></image:image>
+	<video:video>
+		<video:title>A video</video:title>
+		<video:description>About a</video:description>
+		<video:thumbnail_loc>https://example.com/a-thumb.jpg</video:thumbnail_loc>
+		<video:duration>120</video:duration>
+		<video:publication_date>2024-02-23T08:20Z</video:publication_date>
+	</video:video>
+	<news:news>
+		<news:publication>
+			<news:name>Example News</news:name>
+			<news:language>en</news:language>
+		</news:publication>
+		<news:publication_date>2024-02-23T08:20Z</news:publication_date>
+		<news:title>Breaking news</news:title>
+	</news:news>
+	<xhtml:link rel="alternate" hreflang="fr" href="https://example.com/fr/a"/>
+</url>
+</urlset>`
+
+func TestParseReaderNativeExtensions(t *testing.T) {
+	sm, err := gositemap.ParseReaderNative(bytes.NewBufferString(extendedURLSet))
+	assert.NoError(t, err)
+	assertExtensions(t, sm.URLs)
+}
+
+func TestParseReaderOptimizedExtensions(t *testing.T) {
+	sm, err := gositemap.ParseReaderOptimized(bytes.NewBufferString(extendedURLSet))
+	assert.NoError(t, err)
+	assertExtensions(t, sm.URLs)
+}
+
+func assertExtensions(t *testing.T, urls []gositemap.URL) {
+	t.Helper()
+	assert.Len(t, urls, 1)
+	u := urls[0]
+
+	assert.Len(t, u.Images, 1)
+	assert.Equal(t, "https://example.com/a.jpg", u.Images[0].Loc)
+
+	assert.Len(t, u.Videos, 1)
+	assert.Equal(t, "A video", u.Videos[0].Title)
+	assert.Equal(t, "About a", u.Videos[0].Description)
+	assert.Equal(t, "https://example.com/a-thumb.jpg", u.Videos[0].ThumbnailLoc)
+	assert.Equal(t, 120, u.Videos[0].Duration)
+	assert.Equal(t, "2024-02-23T08:20:00Z", u.Videos[0].PublicationDate.Format("2006-01-02T15:04:05Z"))
+
+	assert.NotNil(t, u.News)
+	assert.Equal(t, "Example News", u.News.PublicationName)
+	assert.Equal(t, "en", u.News.PublicationLanguage)
+	assert.Equal(t, "Breaking news", u.News.Title)
+	assert.Equal(t, "2024-02-23T08:20:00Z", u.News.PublicationDate.Format("2006-01-02T15:04:05Z"))
+
+	assert.Len(t, u.Alternates, 1)
+	assert.Equal(t, "fr", u.Alternates[0].Hreflang)
+	assert.Equal(t, "https://example.com/fr/a", u.Alternates[0].Href)
+}
+
+func TestParseReaderOptimizedExtensionsIgnoresNonAlternateLinks(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset><url><loc>https://example.com/a</loc><xhtml:link rel="canonical" href="https://example.com/a"/></url></urlset>`)
+
+	sm, err := gositemap.ParseReaderOptimized(content)
+	assert.NoError(t, err)
+	assert.Len(t, sm.URLs, 1)
+	assert.Empty(t, sm.URLs[0].Alternates)
+}
+
+func TestParseReaderNativeExtensionsIgnoresNonAlternateLinks(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset xmlns:xhtml="http://www.w3.org/1999/xhtml"><url><loc>https://example.com/a</loc><xhtml:link rel="canonical" href="https://example.com/a"/></url></urlset>`)
+
+	sm, err := gositemap.ParseReaderNative(content)
+	assert.NoError(t, err)
+	assert.Len(t, sm.URLs, 1)
+	assert.Empty(t, sm.URLs[0].Alternates)
+}
+
+func TestParseReaderOptimizedExtensionsMultipleImages(t *testing.T) {
+	content := bytes.NewBufferString(fmt.Sprintf(`<urlset><url><loc>https://example.com/a</loc>%s%s</url></urlset>`,
+		`<image:image><image:loc>https://example.com/1.jpg</image:loc></image:image>`,
+		`<image:image><image:loc>https://example.com/2.jpg</image:loc></image:image>`))
+
+	sm, err := gositemap.ParseReaderOptimized(content)
+	assert.NoError(t, err)
+	assert.Len(t, sm.URLs, 1)
+	assert.Len(t, sm.URLs[0].Images, 2)
+	assert.Equal(t, "https://example.com/1.jpg", sm.URLs[0].Images[0].Loc)
+	assert.Equal(t, "https://example.com/2.jpg", sm.URLs[0].Images[1].Loc)
+}
@@ -0,0 +1,113 @@
+package gositemap_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gositemap "github.com/tmeire/go-sitemap"
+)
+
+func TestParseReaderNativeLastmodFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		lastmod string
+		want    string
+	}{
+		{"minute-precision", "2024-02-23T08:20Z", "2024-02-23T08:20:00Z"},
+		{"rfc3339", "2024-02-23T08:20:05Z", "2024-02-23T08:20:05Z"},
+		{"rfc3339-nano", "2024-02-23T08:20:05.123456789Z", "2024-02-23T08:20:05Z"},
+		{"date-only", "2024-02-23", "2024-02-23T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := bytes.NewBufferString(fmt.Sprintf(`<urlset><url><loc>https://example.com/a</loc><lastmod>%s</lastmod></url></urlset>`, tt.lastmod))
+
+			sm, err := gositemap.ParseReaderNative(content)
+			assert.NoError(t, err)
+			assert.Len(t, sm.URLs, 1)
+			assert.Equal(t, tt.want, sm.URLs[0].Lastmod.Format("2006-01-02T15:04:05Z"))
+		})
+	}
+}
+
+func TestParseReaderOptimizedLastmodFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		lastmod string
+		want    string
+	}{
+		{"minute-precision", "2024-02-23T08:20Z", "2024-02-23T08:20:00Z"},
+		{"rfc3339", "2024-02-23T08:20:05Z", "2024-02-23T08:20:05Z"},
+		{"rfc3339-nano", "2024-02-23T08:20:05.123456789Z", "2024-02-23T08:20:05Z"},
+		{"date-only", "2024-02-23", "2024-02-23T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := bytes.NewBufferString(fmt.Sprintf(`<urlset><url><loc>https://example.com/a</loc><lastmod>%s</lastmod></url></urlset>`, tt.lastmod))
+
+			sm, err := gositemap.ParseReaderOptimized(content)
+			assert.NoError(t, err)
+			assert.Len(t, sm.URLs, 1)
+			assert.Equal(t, tt.want, sm.URLs[0].Lastmod.Format("2006-01-02T15:04:05Z"))
+		})
+	}
+}
+
+func TestParseReaderOptimizedWithOptionsLastmodStrict(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset><url><loc>https://example.com/a</loc><lastmod>not-a-date</lastmod></url></urlset>`)
+
+	_, err := gositemap.ParseReaderOptimizedWithOptions(content, gositemap.WithLastmodPolicy(gositemap.LastmodStrict))
+	assert.Error(t, err)
+}
+
+func TestParseReaderOptimizedWithOptionsLastmodDefaultsToLenient(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset><url><loc>https://example.com/a</loc><lastmod>not-a-date</lastmod></url></urlset>`)
+
+	sm, err := gositemap.ParseReaderOptimizedWithOptions(content)
+	assert.NoError(t, err)
+	assert.Len(t, sm.URLs, 1)
+	assert.True(t, sm.URLs[0].Lastmod.IsZero())
+}
+
+func TestParseReaderOptimizedWithOptionsLastmodLenient(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset><url><loc>https://example.com/a</loc><lastmod>not-a-date</lastmod></url></urlset>`)
+
+	sm, err := gositemap.ParseReaderOptimizedWithOptions(content, gositemap.WithLastmodPolicy(gositemap.LastmodLenient))
+	assert.NoError(t, err)
+	assert.Len(t, sm.URLs, 1)
+	assert.Equal(t, "https://example.com/a", sm.URLs[0].Loc)
+	assert.True(t, sm.URLs[0].Lastmod.IsZero())
+}
+
+func TestParseReaderOptimizedWithOptionsLastmodSkipOnError(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset>` +
+		`<url><loc>https://example.com/a</loc><lastmod>not-a-date</lastmod></url>` +
+		`<url><loc>https://example.com/b</loc><lastmod>2024-02-23</lastmod></url>` +
+		`</urlset>`)
+
+	sm, err := gositemap.ParseReaderOptimizedWithOptions(content, gositemap.WithLastmodPolicy(gositemap.LastmodSkipOnError))
+	assert.NoError(t, err)
+	assert.Len(t, sm.URLs, 1)
+	assert.Equal(t, "https://example.com/b", sm.URLs[0].Loc)
+}
+
+func TestParseSiteMapReaderStreamLastmodSkipOnError(t *testing.T) {
+	content := bytes.NewBufferString(`<sitemapindex>` +
+		`<sitemap><loc>https://example.com/a.xml</loc><lastmod>not-a-date</lastmod></sitemap>` +
+		`<sitemap><loc>https://example.com/b.xml</loc><lastmod>2024-02-23</lastmod></sitemap>` +
+		`</sitemapindex>`)
+
+	var maps []gositemap.SiteMap
+	err := gositemap.ParseSiteMapReaderStream(content, func(sm gositemap.SiteMap) error {
+		maps = append(maps, sm)
+		return nil
+	}, gositemap.WithLastmodPolicy(gositemap.LastmodSkipOnError))
+
+	assert.NoError(t, err)
+	assert.Len(t, maps, 1)
+	assert.Equal(t, "https://example.com/b.xml", maps[0].Loc)
+}
@@ -2,10 +2,12 @@ package gositemap
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -78,6 +80,83 @@ type TimeISO3339 struct {
 
 const formatISO3339NoMinutes = "2006-01-02T15:04Z"
 
+// lastmodFormats are tried in order by parseLastmod: the minute-precision
+// format the sitemaps.org examples use, full RFC 3339 (with and without
+// sub-second precision), and a bare date, all of which are valid W3C
+// Datetime values.
+var lastmodFormats = []string{
+	formatISO3339NoMinutes,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+// parseLastmod parses text as a W3C Datetime value, trying each of
+// lastmodFormats in turn and returning the error from the last attempt if
+// none of them match.
+func parseLastmod(text string) (time.Time, error) {
+	var err error
+	for _, format := range lastmodFormats {
+		var t time.Time
+		t, err = time.Parse(format, text)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// LastmodPolicy controls how ParseReaderStream, ParseReaderOptimizedWithOptions
+// and ParseSiteMapReaderStream handle a <lastmod>/publication_date value
+// that parseLastmod can't make sense of.
+type LastmodPolicy int
+
+const (
+	// LastmodLenient leaves the field at its zero value and keeps parsing.
+	// This is the default.
+	LastmodLenient LastmodPolicy = iota
+	// LastmodStrict fails parsing with an error.
+	LastmodStrict
+	// LastmodSkipOnError drops the enclosing entry instead of passing it to
+	// the caller's callback.
+	LastmodSkipOnError
+)
+
+// resolveLastmod parses text as a lastmod value according to policy. When
+// skip is true (err is then nil), the caller should skip the entry text was
+// found in rather than treat it as an error.
+func resolveLastmod(text string, policy LastmodPolicy) (t time.Time, skip bool, err error) {
+	t, perr := parseLastmod(text)
+	if perr == nil {
+		return t, false, nil
+	}
+	switch policy {
+	case LastmodSkipOnError:
+		return time.Time{}, true, nil
+	case LastmodStrict:
+		return time.Time{}, false, fmt.Errorf("invalid lastmod value %q: %w", text, perr)
+	default:
+		return time.Time{}, false, nil
+	}
+}
+
+// ParseOption configures ParseReaderStream, ParseReaderOptimizedWithOptions
+// and ParseSiteMapReaderStream.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	lastmodPolicy LastmodPolicy
+}
+
+// WithLastmodPolicy sets how a <lastmod>/publication_date value that
+// parseLastmod can't make sense of is handled. The default is
+// LastmodLenient.
+func WithLastmodPolicy(policy LastmodPolicy) ParseOption {
+	return func(c *parseConfig) {
+		c.lastmodPolicy = policy
+	}
+}
+
 func (t *TimeISO3339) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	var v string
 	err := d.DecodeElement(&v, &start)
@@ -85,7 +164,7 @@ func (t *TimeISO3339) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error
 		return err
 	}
 
-	tt, err := time.Parse(formatISO3339NoMinutes, v)
+	tt, err := parseLastmod(v)
 	if err != nil {
 		return err
 	}
@@ -129,6 +208,32 @@ type URL struct {
 	Lastmod    TimeISO3339     `xml:"lastmod"`
 	Changefreq Frequency       `xml:"changefreq"`
 	Priority   *BoundedFloat64 `xml:"priority"`
+	Images     []Image         `xml:"http://www.google.com/schemas/sitemap-image/1.1 image"`
+	Videos     []Video         `xml:"http://www.google.com/schemas/sitemap-video/1.1 video"`
+	News       *News           `xml:"http://www.google.com/schemas/sitemap-news/0.9 news"`
+	Alternates []Alternate     `xml:"http://www.w3.org/1999/xhtml link"`
+}
+
+// UnmarshalXML decodes a <url> element, then drops any xhtml:link whose
+// rel isn't "alternate" from Alternates, matching the optimized parser
+// (which only ever registers rel="alternate" links in the first place).
+func (u *URL) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type alias URL
+	var a alias
+	if err := d.DecodeElement(&a, &start); err != nil {
+		return err
+	}
+
+	alternates := a.Alternates[:0]
+	for _, alt := range a.Alternates {
+		if alt.Rel == "alternate" {
+			alternates = append(alternates, alt)
+		}
+	}
+	a.Alternates = alternates
+
+	*u = URL(a)
+	return nil
 }
 
 type URLSet struct {
@@ -195,18 +300,294 @@ const (
 	lastmod
 	priority
 	changefreq
+	image
+	imageLoc
+	video
+	videoTitle
+	videoDescription
+	videoThumbnailLoc
+	videoDuration
+	videoPublicationDate
+	news
+	newsPublication
+	newsPublicationName
+	newsPublicationLanguage
+	newsPublicationDate
+	newsTitle
+	xhtmlLink
+)
+
+// urlParser holds the in-progress URL (and whichever extension element is
+// currently open) while ParseReaderStream walks a <url> element.
+type urlParser struct {
+	fn     func(URL) error
+	policy LastmodPolicy
+	skip   bool
+	url    *URL
+	image  *Image
+	video  *Video
+	news   *News
+}
+
+// elementDef describes how the table-driven tokenizer handles one child
+// tag name within a given parent parseLevel. Adding support for a new
+// extension element is a registerElement call, not another nested switch.
+type elementDef struct {
+	// level is the state entered on the open tag; its matching close tag
+	// looks this elementDef back up via closeTable to know how to return
+	// to parent.
+	level parseLevel
+	// parent is the state to return to once this element is closed. Set
+	// automatically by registerElement.
+	parent parseLevel
+	// container is true for elements that hold nested elements rather
+	// than a single text value.
+	container bool
+	// onOpen runs when a container element's open tag is seen.
+	onOpen func(p *urlParser)
+	// onClose runs for a leaf element once its closing tag is seen, with
+	// the text between the open and close tags.
+	onClose func(p *urlParser, text string) error
+	// onPop runs for a container element once its closing tag is seen.
+	onPop func(p *urlParser) error
+	// onAttrs runs immediately when an element's open tag is seen, for
+	// elements whose data lives in attributes (e.g. xhtml:link) rather
+	// than child text.
+	onAttrs func(p *urlParser, attrs string)
+}
+
+var (
+	openTable  = map[parseLevel]map[string]*elementDef{}
+	closeTable = map[parseLevel]*elementDef{}
 )
 
+// registerElement makes name a valid child of parent in the tokenizer's
+// dispatch table.
+func registerElement(parent parseLevel, name string, def *elementDef) {
+	def.parent = parent
+	if openTable[parent] == nil {
+		openTable[parent] = map[string]*elementDef{}
+	}
+	openTable[parent][name] = def
+	closeTable[def.level] = def
+}
+
+func init() {
+	registerElement(root, "urlset", &elementDef{level: urlset, container: true})
+	registerElement(urlset, "url", &elementDef{
+		level:     url,
+		container: true,
+		onOpen:    func(p *urlParser) { p.url = &URL{} },
+		onPop: func(p *urlParser) error {
+			if p.skip {
+				p.skip = false
+				p.url = nil
+				return nil
+			}
+			err := p.fn(*p.url)
+			p.url = nil
+			return err
+		},
+	})
+	registerElement(url, "loc", &elementDef{
+		level:   loc,
+		onClose: func(p *urlParser, text string) error { p.url.Loc = text; return nil },
+	})
+	registerElement(url, "lastmod", &elementDef{
+		level: lastmod,
+		onClose: func(p *urlParser, text string) error {
+			tt, skip, err := resolveLastmod(text, p.policy)
+			if err != nil {
+				return fmt.Errorf("unexpected value %q for lastmod", text)
+			}
+			if skip {
+				p.skip = true
+				return nil
+			}
+			p.url.Lastmod.Time = tt
+			return nil
+		},
+	})
+	registerElement(url, "priority", &elementDef{
+		level: priority,
+		onClose: func(p *urlParser, text string) error {
+			f, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				f = .5
+			}
+			if f < 0. {
+				f = 0.
+			}
+			if f > 1. {
+				f = 1.
+			}
+			if math.IsNaN(f) {
+				f = .5
+			}
+			p.url.Priority = (*BoundedFloat64)(&f)
+			return nil
+		},
+	})
+	registerElement(url, "changefreq", &elementDef{
+		level: changefreq,
+		onClose: func(p *urlParser, text string) error {
+			switch text {
+			case "always":
+				p.url.Changefreq = ALWAYS
+			case "hourly":
+				p.url.Changefreq = HOURLY
+			case "daily":
+				p.url.Changefreq = DAILY
+			case "weekly":
+				p.url.Changefreq = WEEKLY
+			case "monthly":
+				p.url.Changefreq = MONTHLY
+			case "yearly":
+				p.url.Changefreq = YEARLY
+			case "never":
+				p.url.Changefreq = NEVER
+			default:
+				p.url.Changefreq = UKNOWN
+			}
+			return nil
+		},
+	})
+}
+
+// openTag is a single start or end tag read from the buffer, e.g.
+// "<image:loc>" or "<xhtml:link rel=\"alternate\" .../>".
+type openTag struct {
+	name        string
+	attrs       string
+	closing     bool
+	selfClosing bool
+}
+
+// readTag parses the tag starting at bs[i] ('<') within bs[:n]. ok is
+// false when the tag is not fully contained in the buffer, in which case
+// the caller should request more data and retry. next is the index of the
+// first byte after the tag's closing '>'.
+func readTag(bs []byte, i, n int) (tag openTag, next int, ok bool) {
+	j := i + 1
+	if j < n && bs[j] == '/' {
+		tag.closing = true
+		j++
+	}
+	start := j
+	for j < n && !isTagNameEnd(bs[j]) {
+		j++
+	}
+	if j >= n {
+		return openTag{}, 0, false
+	}
+	tag.name = string(bs[start:j])
+
+	attrStart := j
+	for j < n && bs[j] != '>' {
+		j++
+	}
+	if j >= n {
+		return openTag{}, 0, false
+	}
+	tag.attrs = string(bs[attrStart:j])
+	tag.selfClosing = j > attrStart && bs[j-1] == '/'
+	return tag, j + 1, true
+}
+
+func isTagNameEnd(b byte) bool {
+	return b == '>' || b == '/' || b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// attrValue returns the value of attribute key within a raw attribute
+// string such as `rel="alternate" hreflang="de" href="https://..."`.
+func attrValue(attrs, key string) string {
+	for len(attrs) > 0 {
+		idx := strings.Index(attrs, key+"=")
+		if idx < 0 {
+			return ""
+		}
+		if idx > 0 && attrs[idx-1] != ' ' {
+			attrs = attrs[idx+len(key):]
+			continue
+		}
+		rest := attrs[idx+len(key)+1:]
+		if rest == "" {
+			return ""
+		}
+		quote := rest[0]
+		end := strings.IndexByte(rest[1:], quote)
+		if end < 0 {
+			return ""
+		}
+		return rest[1 : 1+end]
+	}
+	return ""
+}
+
+// matchLiteral reports whether bs[i:n] starts with lit. needMore is true
+// when bs doesn't yet hold enough bytes to tell either way, so the caller
+// should request a refill and retry rather than treat it as a mismatch.
+func matchLiteral(bs []byte, i, n int, lit string) (matched, needMore bool) {
+	if i+len(lit) > n {
+		return false, true
+	}
+	return string(bs[i:i+len(lit)]) == lit, false
+}
+
+// growBuffer doubles bs's capacity, preserving its first valid bytes. It's
+// used when a single tag or run of text doesn't fit in the buffer at all,
+// so discarding already-scanned bytes wouldn't free up any room for a
+// refill.
+func growBuffer(bs []byte, valid int) []byte {
+	grown := make([]byte, len(bs)*2)
+	copy(grown, bs[:valid])
+	return grown
+}
+
 const bufferSize = 4096
-const breakoutThreshold = 75
 
+// ErrStop can be returned from a ParseReaderStream or
+// ParseSiteMapReaderStream callback to stop parsing early without that
+// being treated as an error.
+var ErrStop = errors.New("gositemap: stop")
+
+// ParseReaderOptimized parses a <urlset> document, returning every URL it
+// contains. It is a thin wrapper around ParseReaderStream for callers that
+// want the whole result in memory.
 func ParseReaderOptimized(content io.Reader) (*SiteMapOrURLSet, error) {
+	return ParseReaderOptimizedWithOptions(content)
+}
+
+// ParseReaderOptimizedWithOptions is ParseReaderOptimized with control over
+// how an unparseable <lastmod> value is handled; see LastmodPolicy.
+func ParseReaderOptimizedWithOptions(content io.Reader, opts ...ParseOption) (*SiteMapOrURLSet, error) {
+	var urls []URL
+	err := ParseReaderStream(content, func(u URL) error {
+		urls = append(urls, u)
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &SiteMapOrURLSet{URLs: urls}, nil
+}
+
+// ParseReaderStream parses a <urlset> document, calling fn for each <url>
+// element as soon as its closing tag is seen. The parser never retains
+// more than one URL at a time, so memory use stays O(1) regardless of how
+// large the feed is. fn may return ErrStop to stop parsing early without
+// that being treated as an error.
+func ParseReaderStream(content io.Reader, fn func(URL) error, opts ...ParseOption) error {
+	var cfg parseConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+
 	bs := make([]byte, bufferSize)
 	n, err := content.Read(bs)
-	currentParseLevel := root
+	p := &urlParser{fn: fn, policy: cfg.lastmodPolicy}
+	currentLevel := root
 	contentStart := -1
-	var currentURLSet URLSet
-	var currentURL *URL
 
 	currentCharacter := 1
 	currentLine := 1
@@ -221,150 +602,280 @@ func ParseReaderOptimized(content io.Reader) (*SiteMapOrURLSet, error) {
 			case '\r', '\t', ' ':
 				continue
 			case '<':
-				if i > (n*breakoutThreshold/100) && bs[i+1] != '/' {
-					fmt.Println("load more data", string(bs[i:n]))
+				if currentLevel == root && i+1 < n && bs[i+1] == '?' {
+					j := i + 2
+					for j+1 < n && !(bs[j] == '?' && bs[j+1] == '>') {
+						j++
+					}
+					if j+1 >= n {
+						resetPosition = i
+						break
+					}
+					i = j + 1
+					break
+				}
+
+				tag, next, ok := readTag(bs, i, n)
+				if !ok {
 					resetPosition = i
 					break
 				}
-				// [/] urlset, url, loc, lastmod, priority, changefreq
-				switch currentParseLevel {
-				case root:
-					if bs[i+1] == '?' {
-						for j := i + 2; bs[j] != '?' && bs[j+1] != '>'; j++ {
-							i++
-						}
-						i += 3
-						break
-					}
-					if string(bs[i+1:i+8]) != "urlset>" {
-						return nil, fmt.Errorf("unexpected tag at line %d : position %d", currentLine, currentCharacter)
-					}
-					currentURLSet = URLSet{}
-					currentParseLevel = urlset
-					i += 7
-				case urlset:
-					if string(bs[i+1:i+5]) == "url>" {
-						currentURL = &URL{}
-						currentParseLevel = url
-						i += 4
-					} else if string(bs[i+1:i+9]) == "/urlset>" {
-						currentParseLevel = root
-						i += 8
-					} else {
-						return nil, fmt.Errorf("unexpected tag at  line %d : position %d", currentLine, currentCharacter)
+
+				if tag.closing {
+					def, known := closeTable[currentLevel]
+					if !known {
+						return fmt.Errorf("unexpected closing tag %q at line %d : position %d", tag.name, currentLine, currentCharacter)
 					}
-				case url:
-					switch bs[i+2] {
-					case 'o': // loc
-						if string(bs[i+1:i+5]) != "loc>" {
-							return nil, fmt.Errorf("unexpected tag at line %d : position %dexpected 'loc'", currentLine, currentCharacter)
+					if def.container {
+						if def.onPop != nil {
+							if err := def.onPop(p); err != nil {
+								if err == ErrStop {
+									return nil
+								}
+								return err
+							}
 						}
-						contentStart = i + 5
-						currentParseLevel = loc
-						i += 4
-					case 'a': // lastmod
-						if string(bs[i+1:i+9]) != "lastmod>" {
-							return nil, fmt.Errorf("unexpected tag at line %d : position %dexpected 'lastmod'", currentLine, currentCharacter)
+					} else if def.onClose != nil {
+						if err := def.onClose(p, string(bs[contentStart:i])); err != nil {
+							return err
 						}
-						contentStart = i + 9
-						currentParseLevel = lastmod
-						i += 8
-					case 'r': // priority
-						if string(bs[i+1:i+10]) != "priority>" {
-							return nil, fmt.Errorf("unexpected tag at line %d : position %d, expected 'priority'", currentLine, currentCharacter)
+					}
+					currentLevel = def.parent
+					i = next - 1
+					break
+				}
+
+				def, known := openTable[currentLevel][tag.name]
+				if !known {
+					return fmt.Errorf("unexpected tag %q at line %d : position %d", tag.name, currentLine, currentCharacter)
+				}
+
+				if def.onAttrs != nil {
+					def.onAttrs(p, tag.attrs)
+				}
+				if def.onOpen != nil {
+					def.onOpen(p)
+				}
+
+				if tag.selfClosing {
+					if def.container && def.onPop != nil {
+						if err := def.onPop(p); err != nil {
+							if err == ErrStop {
+								return nil
+							}
+							return err
 						}
-						contentStart = i + 10
-						currentParseLevel = priority
-						i += 9
-					case 'h': // changefreq
-						if string(bs[i+1:i+12]) != "changefreq>" {
-							return nil, fmt.Errorf("unexpected tag at line %d : position %d, expected 'changefreq'", currentLine, currentCharacter)
+					}
+					// currentLevel is unchanged: a self-closing element
+					// never has a separate closing tag to return from.
+				} else {
+					contentStart = next
+					currentLevel = def.level
+				}
+				i = next - 1
+			default:
+				if def, known := closeTable[currentLevel]; !known || def.container {
+					return fmt.Errorf("unexpected character %q at line %d : position %d", bs[i], currentLine, currentCharacter)
+				}
+			}
+			if resetPosition != -1 {
+				break
+			}
+			currentCharacter++
+		}
+
+		// keepFrom is the earliest buffered byte that must survive a
+		// refill. It's usually resetPosition (an in-progress tag that got
+		// split across the buffer boundary), but if we're in the middle of
+		// capturing a leaf element's text, that text's start must be kept
+		// too even if it begins earlier than the split tag.
+		keepFrom := resetPosition
+		if def, known := closeTable[currentLevel]; known && !def.container {
+			if keepFrom == -1 || contentStart < keepFrom {
+				keepFrom = contentStart
+			}
+		}
+
+		offset := 0
+		if keepFrom >= 0 {
+			if keepFrom > 0 {
+				copy(bs, bs[keepFrom:n])
+			}
+			contentStart -= keepFrom
+			offset = n - keepFrom
+		}
+		if offset == len(bs) {
+			bs = growBuffer(bs, offset)
+		}
+		n, err = content.Read(bs[offset:])
+		n += offset
+	}
+
+	if err != io.EOF {
+		return err
+	}
+
+	return nil
+}
+
+type smParseLevel int
+
+const (
+	smRoot smParseLevel = iota
+	smIndex
+	smEntry
+	smLoc
+	smLastmod
+)
+
+// ParseSiteMapReaderStream is the <sitemapindex> sibling of
+// ParseReaderStream: it parses a <sitemapindex> document, calling fn for
+// each <sitemap> element as soon as its closing tag is seen, and never
+// retains more than one entry at a time. fn may return ErrStop to stop
+// parsing early without that being treated as an error.
+func ParseSiteMapReaderStream(content io.Reader, fn func(SiteMap) error, opts ...ParseOption) error {
+	var cfg parseConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	bs := make([]byte, bufferSize)
+	n, err := content.Read(bs)
+	currentParseLevel := smRoot
+	contentStart := -1
+	var currentSiteMap *SiteMap
+	var skipCurrent bool
+
+	currentCharacter := 1
+	currentLine := 1
+	for err == nil {
+		resetPosition := -1
+		for i := 0; i < n; i++ {
+			switch bs[i] {
+			case '\n':
+				currentLine++
+				currentCharacter = 1
+				continue
+			case '\r', '\t', ' ':
+				continue
+			case '<':
+				// [/] sitemapindex, sitemap, loc, lastmod
+				switch currentParseLevel {
+				case smRoot:
+					if i+1 < n && bs[i+1] == '?' {
+						j := i + 2
+						for j+1 < n && !(bs[j] == '?' && bs[j+1] == '>') {
+							j++
 						}
-						contentStart = i + 12
-						currentParseLevel = changefreq
-						i += 11
-					case 'u': // close the url element
-						if string(bs[i+1:i+6]) != "/url>" {
-							return nil, fmt.Errorf("unexpected tag at line %d : position %d, expected 'changefreq'", currentLine, currentCharacter)
+						if j+1 >= n {
+							resetPosition = i
+							break
 						}
-						currentURLSet.Urls = append(currentURLSet.Urls, *currentURL)
-						currentURL = nil
-						currentParseLevel = urlset
-						i += 5
-					default:
-						return nil, fmt.Errorf("unexpected tag at line %d : position %d", currentLine, currentCharacter)
-					}
-				case loc:
-					if string(bs[i+1:i+6]) != "/loc>" {
-						return nil, fmt.Errorf("unexpected tag at line %d : position %d, expected '</loc>'", currentLine, currentCharacter)
+						i = j + 1
+						break
 					}
-					currentURL.Loc = string(bs[contentStart:i])
-					currentParseLevel = url
-					i += 5
-				case changefreq:
-					if string(bs[i+1:i+13]) != "/changefreq>" {
-						return nil, fmt.Errorf("unexpected tag at line %d : position %d, expected '</changefreq>'", currentLine, currentCharacter)
+					matched, needMore := matchLiteral(bs, i+1, n, "sitemapindex>")
+					if needMore {
+						resetPosition = i
+						break
 					}
-
-					switch string(bs[contentStart:i]) {
-					case "always":
-						currentURL.Changefreq = ALWAYS
-					case "hourly":
-						currentURL.Changefreq = HOURLY
-					case "daily":
-						currentURL.Changefreq = DAILY
-					case "weekly":
-						currentURL.Changefreq = WEEKLY
-					case "monthly":
-						currentURL.Changefreq = MONTHLY
-					case "yearly":
-						currentURL.Changefreq = YEARLY
-					case "never":
-						currentURL.Changefreq = NEVER
-					default:
-						currentURL.Changefreq = UKNOWN
+					if !matched {
+						return fmt.Errorf("unexpected tag at line %d : position %d", currentLine, currentCharacter)
 					}
-					currentParseLevel = url
-					i += 12
-				case priority:
-					if string(bs[i+1:i+11]) != "/priority>" {
-						return nil, fmt.Errorf("unexpected tag at line %d : position %d, expected '</priority>'", currentLine, currentCharacter)
+					currentParseLevel = smIndex
+					i += len("sitemapindex>")
+				case smIndex:
+					if matched, needMore := matchLiteral(bs, i+1, n, "sitemap>"); needMore {
+						resetPosition = i
+						break
+					} else if matched {
+						currentSiteMap = &SiteMap{}
+						currentParseLevel = smEntry
+						i += len("sitemap>")
+						break
 					}
-					f, err := strconv.ParseFloat(string(bs[contentStart:i]), 64)
-					if err != nil {
-						f = .5
+					if matched, needMore := matchLiteral(bs, i+1, n, "/sitemapindex>"); needMore {
+						resetPosition = i
+						break
+					} else if matched {
+						currentParseLevel = smRoot
+						i += len("/sitemapindex>")
+						break
 					}
-
-					if f < 0. {
-						f = 0.
+					return fmt.Errorf("unexpected tag at line %d : position %d", currentLine, currentCharacter)
+				case smEntry:
+					if matched, needMore := matchLiteral(bs, i+1, n, "loc>"); needMore {
+						resetPosition = i
+						break
+					} else if matched {
+						contentStart = i + 1 + len("loc>")
+						currentParseLevel = smLoc
+						i += len("loc>")
+						break
 					}
-					if f > 1. {
-						f = 1.
+					if matched, needMore := matchLiteral(bs, i+1, n, "lastmod>"); needMore {
+						resetPosition = i
+						break
+					} else if matched {
+						contentStart = i + 1 + len("lastmod>")
+						currentParseLevel = smLastmod
+						i += len("lastmod>")
+						break
 					}
-					if math.IsNaN(f) {
-						f = .5
+					if matched, needMore := matchLiteral(bs, i+1, n, "/sitemap>"); needMore {
+						resetPosition = i
+						break
+					} else if matched {
+						if !skipCurrent {
+							if err := fn(*currentSiteMap); err != nil {
+								if err == ErrStop {
+									return nil
+								}
+								return err
+							}
+						}
+						currentSiteMap = nil
+						skipCurrent = false
+						currentParseLevel = smIndex
+						i += len("/sitemap>")
+						break
 					}
-					currentURL.Priority = (*BoundedFloat64)(&f)
-					currentParseLevel = url
-					i += 10
-				case lastmod:
-					if string(bs[i+1:i+10]) != "/lastmod>" {
-						return nil, fmt.Errorf("unexpected tag at line %d : position %d, expected '</lastmod>'", currentLine, currentCharacter)
+					return fmt.Errorf("unexpected tag at line %d : position %d", currentLine, currentCharacter)
+				case smLoc:
+					if matched, needMore := matchLiteral(bs, i+1, n, "/loc>"); needMore {
+						resetPosition = i
+						break
+					} else if matched {
+						currentSiteMap.Loc = string(bs[contentStart:i])
+						currentParseLevel = smEntry
+						i += len("/loc>")
+						break
 					}
-
-					tt, err := time.Parse(formatISO3339NoMinutes, string(bs[contentStart:i]))
-					if err != nil {
-						return nil, fmt.Errorf("unexpected value %s for lastmod at line %d : position %d", string(bs[contentStart:i]), currentLine, (currentCharacter - i + contentStart))
+					return fmt.Errorf("unexpected tag at line %d : position %d, expected '</loc>'", currentLine, currentCharacter)
+				case smLastmod:
+					if matched, needMore := matchLiteral(bs, i+1, n, "/lastmod>"); needMore {
+						resetPosition = i
+						break
+					} else if matched {
+						tt, skip, err := resolveLastmod(string(bs[contentStart:i]), cfg.lastmodPolicy)
+						if err != nil {
+							return fmt.Errorf("unexpected value %s for lastmod at line %d : position %d", string(bs[contentStart:i]), currentLine, currentCharacter)
+						}
+						if skip {
+							skipCurrent = true
+						} else {
+							currentSiteMap.Lastmod.Time = tt
+						}
+						currentParseLevel = smEntry
+						i += len("/lastmod>")
+						break
 					}
-
-					currentURL.Lastmod.Time = tt
-					currentParseLevel = url
-					i += 9
+					return fmt.Errorf("unexpected tag at line %d : position %d, expected '</lastmod>'", currentLine, currentCharacter)
 				}
 			default:
 				switch currentParseLevel {
-				case root, urlset, url:
-					return nil, fmt.Errorf("unexpected character %q at line %d : position %d", bs[i], currentLine, currentCharacter)
+				case smRoot, smIndex, smEntry:
+					return fmt.Errorf("unexpected character %q at line %d : position %d", bs[i], currentLine, currentCharacter)
 				}
 			}
 			if resetPosition != -1 {
@@ -372,20 +883,35 @@ func ParseReaderOptimized(content io.Reader) (*SiteMapOrURLSet, error) {
 			}
 			currentCharacter++
 		}
+
+		// See the matching comment in ParseReaderStream: keepFrom must
+		// also cover a <loc>/<lastmod> value in progress, since its start
+		// can precede a split closing tag.
+		keepFrom := resetPosition
+		if currentParseLevel == smLoc || currentParseLevel == smLastmod {
+			if keepFrom == -1 || contentStart < keepFrom {
+				keepFrom = contentStart
+			}
+		}
+
 		offset := 0
-		if resetPosition != -1 {
-			copy(bs, bs[resetPosition:n])
-			offset = n - resetPosition
+		if keepFrom >= 0 {
+			if keepFrom > 0 {
+				copy(bs, bs[keepFrom:n])
+			}
+			contentStart -= keepFrom
+			offset = n - keepFrom
+		}
+		if offset == len(bs) {
+			bs = growBuffer(bs, offset)
 		}
 		n, err = content.Read(bs[offset:])
 		n += offset
 	}
 
 	if err != io.EOF {
-		return nil, err
+		return err
 	}
 
-	return &SiteMapOrURLSet{
-		URLs: currentURLSet.Urls,
-	}, nil
+	return nil
 }
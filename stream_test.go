@@ -0,0 +1,76 @@
+package gositemap_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gositemap "github.com/tmeire/go-sitemap"
+)
+
+func TestParseReaderStream(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`)
+
+	var locs []string
+	err := gositemap.ParseReaderStream(content, func(u gositemap.URL) error {
+		locs = append(locs, u.Loc)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, locs)
+}
+
+func TestParseReaderStreamStopsEarly(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`)
+
+	var locs []string
+	err := gositemap.ParseReaderStream(content, func(u gositemap.URL) error {
+		locs = append(locs, u.Loc)
+		return gositemap.ErrStop
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/a"}, locs)
+}
+
+func TestParseReaderStreamPropagatesCallbackError(t *testing.T) {
+	content := bytes.NewBufferString(`<urlset><url><loc>https://example.com/a</loc></url></urlset>`)
+
+	boom := fmt.Errorf("boom")
+	err := gositemap.ParseReaderStream(content, func(u gositemap.URL) error {
+		return boom
+	})
+
+	assert.ErrorIs(t, err, boom)
+}
+
+func TestParseSiteMapReaderStream(t *testing.T) {
+	content := bytes.NewBufferString(`<sitemapindex><sitemap><loc>https://example.com/a.xml</loc></sitemap><sitemap><loc>https://example.com/b.xml</loc><lastmod>2024-02-23T08:20Z</lastmod></sitemap></sitemapindex>`)
+
+	var maps []gositemap.SiteMap
+	err := gositemap.ParseSiteMapReaderStream(content, func(sm gositemap.SiteMap) error {
+		maps = append(maps, sm)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, maps, 2)
+	assert.Equal(t, "https://example.com/a.xml", maps[0].Loc)
+	assert.Equal(t, "https://example.com/b.xml", maps[1].Loc)
+	assert.Equal(t, "2024-02-23T08:20:00Z", maps[1].Lastmod.Format("2006-01-02T15:04:05Z"))
+}
+
+func TestParseSiteMapReaderStreamStopsEarly(t *testing.T) {
+	content := bytes.NewBufferString(`<sitemapindex><sitemap><loc>https://example.com/a.xml</loc></sitemap><sitemap><loc>https://example.com/b.xml</loc></sitemap></sitemapindex>`)
+
+	var maps []gositemap.SiteMap
+	err := gositemap.ParseSiteMapReaderStream(content, func(sm gositemap.SiteMap) error {
+		maps = append(maps, sm)
+		return gositemap.ErrStop
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, maps, 1)
+}
@@ -0,0 +1,329 @@
+package gositemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	nurl "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WalkFunc is called for every <url> entry discovered while walking a
+// sitemap tree. fn may be called concurrently from multiple goroutines.
+type WalkFunc func(URL) error
+
+type walkerConfig struct {
+	client      *http.Client
+	userAgent   string
+	from, to    time.Time
+	concurrency int
+	ratePerHost time.Duration
+	maxErrors   int
+}
+
+// WalkerOption configures a Walker created with NewWalker.
+type WalkerOption func(*walkerConfig)
+
+// WithHTTPClient overrides the *http.Client used to fetch sitemaps. The
+// default is http.DefaultClient.
+func WithHTTPClient(c *http.Client) WalkerOption {
+	return func(cfg *walkerConfig) { cfg.client = c }
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) WalkerOption {
+	return func(cfg *walkerConfig) { cfg.userAgent = ua }
+}
+
+// WithTimeRange restricts the walk to <sitemap> and <url> entries whose
+// lastmod falls within [from, to]. A zero from or to leaves that bound
+// unset. Entries without a lastmod are always visited.
+func WithTimeRange(from, to time.Time) WalkerOption {
+	return func(cfg *walkerConfig) { cfg.from = from; cfg.to = to }
+}
+
+// WithConcurrency caps the number of sitemaps fetched in parallel. The
+// default is 4.
+func WithConcurrency(n int) WalkerOption {
+	return func(cfg *walkerConfig) { cfg.concurrency = n }
+}
+
+// WithRatePerHost enforces a minimum interval between requests to the same
+// host.
+func WithRatePerHost(d time.Duration) WalkerOption {
+	return func(cfg *walkerConfig) { cfg.ratePerHost = d }
+}
+
+// WithMaxErrors aborts the walk once more than n fetch/parse errors have
+// been observed. The default, 0, means unlimited.
+func WithMaxErrors(n int) WalkerOption {
+	return func(cfg *walkerConfig) { cfg.maxErrors = n }
+}
+
+// Walker recursively fetches a tree of sitemaps over HTTP, descending into
+// <sitemapindex> entries and yielding every <url> it finds. This mirrors
+// the seed-discovery use case common in web crawlers.
+type Walker struct {
+	client      *http.Client
+	userAgent   string
+	from, to    time.Time
+	ratePerHost time.Duration
+	maxErrors   int
+	sem         chan struct{}
+
+	mu      sync.Mutex
+	lastHit map[string]time.Time
+	visited map[string]bool
+	errs    []error
+}
+
+// NewWalker returns a Walker configured by opts.
+func NewWalker(opts ...WalkerOption) *Walker {
+	cfg := walkerConfig{
+		client:      http.DefaultClient,
+		concurrency: 4,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.concurrency < 1 {
+		cfg.concurrency = 1
+	}
+	return &Walker{
+		client:      cfg.client,
+		userAgent:   cfg.userAgent,
+		from:        cfg.from,
+		to:          cfg.to,
+		ratePerHost: cfg.ratePerHost,
+		maxErrors:   cfg.maxErrors,
+		sem:         make(chan struct{}, cfg.concurrency),
+		lastHit:     make(map[string]time.Time),
+		visited:     make(map[string]bool),
+	}
+}
+
+// Walk fetches each of the seeds, recursively descending into any
+// <sitemapindex> it encounters, and calls fn for every <url> entry found.
+// It returns once every reachable sitemap has been visited, the context is
+// cancelled, or the configured error threshold has been exceeded; any
+// fetch, parse, or WalkFunc errors encountered along the way are joined
+// together in the returned error.
+func (w *Walker) Walk(ctx context.Context, fn WalkFunc, seeds ...string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, seed := range seeds {
+		if !w.markVisited(seed) {
+			continue
+		}
+		wg.Add(1)
+		go w.visit(ctx, cancel, &wg, fn, seed)
+	}
+	wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.errs) == 0 {
+		return nil
+	}
+	return errors.Join(w.errs...)
+}
+
+// WalkChan behaves like Walk but delivers URLs on a channel instead of via
+// a callback. The returned URL channel is closed once the walk finishes;
+// the error channel receives at most one value, the error Walk returned.
+func (w *Walker) WalkChan(ctx context.Context, seeds ...string) (<-chan URL, <-chan error) {
+	urls := make(chan URL)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(urls)
+		defer close(errc)
+		err := w.Walk(ctx, func(u URL) error {
+			select {
+			case urls <- u:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, seeds...)
+		if err != nil {
+			errc <- err
+		}
+	}()
+
+	return urls, errc
+}
+
+func (w *Walker) visit(ctx context.Context, cancel context.CancelFunc, wg *sync.WaitGroup, fn WalkFunc, loc string) {
+	defer wg.Done()
+
+	select {
+	case <-ctx.Done():
+		return
+	case w.sem <- struct{}{}:
+	}
+	defer func() { <-w.sem }()
+
+	w.throttle(loc)
+
+	doc, err := w.fetch(ctx, loc)
+	if err != nil {
+		w.recordError(cancel, fmt.Errorf("gositemap: fetching %s: %w", loc, err))
+		return
+	}
+
+	for _, sm := range doc.Maps {
+		if !w.inRange(sm.Lastmod) {
+			continue
+		}
+		if !w.markVisited(sm.Loc) {
+			continue
+		}
+		wg.Add(1)
+		go w.visit(ctx, cancel, wg, fn, sm.Loc)
+	}
+
+	for _, u := range doc.URLs {
+		if !w.inRange(u.Lastmod) {
+			continue
+		}
+		if err := fn(u); err != nil {
+			w.recordError(cancel, fmt.Errorf("gositemap: handling %s: %w", u.Loc, err))
+			return
+		}
+	}
+}
+
+func (w *Walker) recordError(cancel context.CancelFunc, err error) {
+	w.mu.Lock()
+	w.errs = append(w.errs, err)
+	exceeded := w.maxErrors > 0 && len(w.errs) > w.maxErrors
+	w.mu.Unlock()
+	if exceeded {
+		cancel()
+	}
+}
+
+// markVisited reports whether loc is being visited for the first time,
+// recording it as seen either way. A self-referential or cyclic
+// <sitemapindex> would otherwise make the walk recurse forever.
+func (w *Walker) markVisited(loc string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.visited[loc] {
+		return false
+	}
+	w.visited[loc] = true
+	return true
+}
+
+func (w *Walker) inRange(t TimeISO3339) bool {
+	if t.IsZero() {
+		return true
+	}
+	if !w.from.IsZero() && t.Before(w.from) {
+		return false
+	}
+	if !w.to.IsZero() && t.After(w.to) {
+		return false
+	}
+	return true
+}
+
+func (w *Walker) throttle(loc string) {
+	if w.ratePerHost <= 0 {
+		return
+	}
+	u, err := nurl.Parse(loc)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	var wait time.Duration
+	if last, ok := w.lastHit[u.Host]; ok {
+		if d := w.ratePerHost - time.Since(last); d > 0 {
+			wait = d
+		}
+	}
+	w.lastHit[u.Host] = time.Now().Add(wait)
+	w.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func (w *Walker) fetch(ctx context.Context, loc string) (*SiteMapOrURLSet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loc, nil)
+	if err != nil {
+		return nil, err
+	}
+	if w.userAgent != "" {
+		req.Header.Set("User-Agent", w.userAgent)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body := io.Reader(resp.Body)
+	if isGzip(loc, resp.Header.Get("Content-Type"), resp.Header.Get("Content-Encoding"), resp.Uncompressed) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	br := bufio.NewReaderSize(body, 512)
+	peek, _ := br.Peek(512)
+	if bytes.Contains(peek, []byte("<sitemapindex")) {
+		content, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		var maps SiteMaps
+		if err := xml.Unmarshal(content, &maps); err != nil {
+			return nil, err
+		}
+		return &SiteMapOrURLSet{Maps: maps.Maps}, nil
+	}
+
+	return ParseReaderOptimized(br)
+}
+
+// isGzip reports whether a response body needs manual gzip decompression.
+// The standard library already transparently decodes Content-Encoding:
+// gzip (and reports that via resp.Uncompressed, deleting the header in the
+// process), so this only needs to catch sitemaps served as a raw .xml.gz
+// file that the transport didn't already handle.
+func isGzip(loc, contentType, contentEncoding string, uncompressed bool) bool {
+	if uncompressed {
+		return false
+	}
+	if strings.EqualFold(contentEncoding, "gzip") {
+		return true
+	}
+	if strings.Contains(contentType, "gzip") {
+		return true
+	}
+	return strings.HasSuffix(loc, ".gz")
+}
@@ -0,0 +1,186 @@
+package gositemap_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gositemap "github.com/tmeire/go-sitemap"
+)
+
+// rawURLSet builds a minimal <urlset> fixture by hand, with no XML
+// declaration and no xmlns attribute, same as sitemap_test.go does.
+func rawURLSet(locs ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("<urlset>")
+	for _, loc := range locs {
+		fmt.Fprintf(&b, "<url><loc>%s</loc></url>", loc)
+	}
+	b.WriteString("</urlset>")
+	return b.Bytes()
+}
+
+func TestWalkerSingleURLSet(t *testing.T) {
+	body := rawURLSet("https://example.com/a", "https://example.com/b")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var locs []string
+	walker := gositemap.NewWalker()
+	err := walker.Walk(context.Background(), func(u gositemap.URL) error {
+		mu.Lock()
+		locs = append(locs, u.Loc)
+		mu.Unlock()
+		return nil
+	}, srv.URL+"/sitemap.xml")
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"https://example.com/a", "https://example.com/b"}, locs)
+}
+
+func TestWalkerRecursesIntoIndex(t *testing.T) {
+	leafA := rawURLSet("https://example.com/a")
+	leafB := rawURLSet("https://example.com/b")
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			idx, _ := gositemap.SiteMaps{Maps: []gositemap.SiteMap{
+				{Loc: srv.URL + "/a.xml"},
+				{Loc: srv.URL + "/b.xml"},
+			}}.Marshal()
+			_, _ = w.Write(idx)
+		case "/a.xml":
+			_, _ = w.Write(leafA)
+		case "/b.xml":
+			_, _ = w.Write(leafB)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var locs []string
+	walker := gositemap.NewWalker()
+	err := walker.Walk(context.Background(), func(u gositemap.URL) error {
+		mu.Lock()
+		locs = append(locs, u.Loc)
+		mu.Unlock()
+		return nil
+	}, srv.URL+"/index.xml")
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"https://example.com/a", "https://example.com/b"}, locs)
+}
+
+func TestWalkerGzip(t *testing.T) {
+	body := rawURLSet("https://example.com/a")
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write(body)
+	assert.NoError(t, gz.Close())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	var locs []string
+	walker := gositemap.NewWalker()
+	err := walker.Walk(context.Background(), func(u gositemap.URL) error {
+		locs = append(locs, u.Loc)
+		return nil
+	}, srv.URL+"/sitemap.xml.gz")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/a"}, locs)
+}
+
+func TestWalkerTimeRangeSkipsDescent(t *testing.T) {
+	var visitedLeaf bool
+	leaf := rawURLSet("https://example.com/old")
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.xml" {
+			idx := bytes.NewBufferString(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<sitemap><loc>%s/old.xml</loc><lastmod>2010-01-01T00:00Z</lastmod></sitemap>
+</sitemapindex>`, srv.URL))
+			_, _ = w.Write(idx.Bytes())
+			return
+		}
+		visitedLeaf = true
+		_, _ = w.Write(leaf)
+	}))
+	defer srv.Close()
+
+	walker := gositemap.NewWalker(gositemap.WithTimeRange(time.Now().AddDate(-1, 0, 0), time.Time{}))
+	err := walker.Walk(context.Background(), func(u gositemap.URL) error {
+		return nil
+	}, srv.URL+"/index.xml")
+
+	assert.NoError(t, err)
+	assert.False(t, visitedLeaf, "descent into an out-of-range sitemap should have been skipped")
+}
+
+func TestWalkerSkipsAlreadyVisitedSitemap(t *testing.T) {
+	var hits int32
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		idx, _ := gositemap.SiteMaps{Maps: []gositemap.SiteMap{
+			{Loc: srv.URL + "/index.xml"},
+		}}.Marshal()
+		_, _ = w.Write(idx)
+	}))
+	defer srv.Close()
+
+	walker := gositemap.NewWalker()
+	done := make(chan error, 1)
+	go func() {
+		done <- walker.Walk(context.Background(), func(u gositemap.URL) error {
+			return nil
+		}, srv.URL+"/index.xml")
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Walk did not converge on a self-referential sitemapindex")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits), "a sitemap already visited should not be fetched again")
+}
+
+func TestWalkerMaxErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	walker := gositemap.NewWalker(gositemap.WithMaxErrors(1))
+	err := walker.Walk(context.Background(), func(u gositemap.URL) error {
+		return nil
+	}, srv.URL+"/a.xml", srv.URL+"/b.xml", srv.URL+"/c.xml")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,327 @@
+package gositemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MaxURLsPerFile and MaxSitemapBytes mirror the per-file limits from the
+// sitemaps.org protocol: https://www.sitemaps.org/protocol.html#index
+const (
+	MaxURLsPerFile  = 50000
+	MaxSitemapBytes = 50 * 1024 * 1024
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+// Option configures a Writer or IndexWriter.
+type Option func(*writerConfig)
+
+type writerConfig struct {
+	gzip bool
+}
+
+// WithGzip gzip-compresses the XML document as it is written, suitable for
+// producing .xml.gz output.
+func WithGzip() Option {
+	return func(c *writerConfig) {
+		c.gzip = true
+	}
+}
+
+// MarshalXML omits the element entirely for UKNOWN, since that isn't a
+// valid sitemaps.org changefreq value and would fail XSD validation.
+func (f Frequency) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if f == UKNOWN {
+		return nil
+	}
+	return e.EncodeElement(f.String(), start)
+}
+
+// MarshalXML omits the element entirely for a zero Time, rather than
+// writing out a meaningless "0001-01-01" lastmod. The value is normalized
+// to UTC and written with time.RFC3339 rather than formatISO3339NoMinutes,
+// since that layout's literal "Z" would mislabel a zoned time as UTC and
+// drop sub-minute precision.
+func (t TimeISO3339) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if t.Time.IsZero() {
+		return nil
+	}
+	return e.EncodeElement(t.UTC().Format(time.RFC3339), start)
+}
+
+// Writer streams a <urlset> document to an underlying io.Writer, flushing
+// each <url> element as soon as it is added so memory use stays O(1)
+// regardless of how many URLs the sitemap holds.
+type Writer struct {
+	w      io.Writer
+	closer io.Closer
+	n      int
+	size   int64
+	opened bool
+	closed bool
+}
+
+// NewWriter returns a Writer that writes a <urlset> document to w.
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	var c writerConfig
+	for _, o := range opts {
+		o(&c)
+	}
+	if c.gzip {
+		gz := gzip.NewWriter(w)
+		return &Writer{w: gz, closer: gz}
+	}
+	return &Writer{w: w}
+}
+
+func (w *Writer) open() error {
+	if w.opened {
+		return nil
+	}
+	w.opened = true
+	n, err := io.WriteString(w.w, xmlHeader+`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+"\n")
+	w.size += int64(n)
+	return err
+}
+
+// Add writes a single <url> element. Once adding u would push the document
+// past the sitemaps.org per-file limits (MaxURLsPerFile URLs or
+// MaxSitemapBytes bytes), Add returns an error instead of writing it so the
+// caller can rotate into a new file.
+func (w *Writer) Add(u URL) error {
+	if w.closed {
+		return fmt.Errorf("gositemap: Add called on a closed Writer")
+	}
+	if w.n >= MaxURLsPerFile {
+		return fmt.Errorf("gositemap: sitemap already has the maximum of %d URLs", MaxURLsPerFile)
+	}
+
+	buf, err := xml.Marshal(u)
+	if err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	// Count the newline written after buf and the closing tag that Close
+	// writes, so the finished document stays within MaxSitemapBytes.
+	if w.size+int64(len(buf))+1+int64(len("</urlset>\n")) > MaxSitemapBytes {
+		return fmt.Errorf("gositemap: adding this URL would exceed the %d byte sitemap limit", MaxSitemapBytes)
+	}
+
+	n, err := w.w.Write(buf)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	n, err = io.WriteString(w.w, "\n")
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	w.n++
+	return nil
+}
+
+// Close writes the closing </urlset> tag and, for a gzip Writer, flushes
+// and closes the underlying gzip stream.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.open(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.w, "</urlset>\n"); err != nil {
+		return err
+	}
+	if w.closer != nil {
+		return w.closer.Close()
+	}
+	return nil
+}
+
+// IndexWriter streams a <sitemapindex> document to an underlying
+// io.Writer, flushing each <sitemap> element as soon as it is added.
+type IndexWriter struct {
+	w      io.Writer
+	closer io.Closer
+	opened bool
+	closed bool
+}
+
+// NewIndexWriter returns an IndexWriter that writes a <sitemapindex>
+// document to w.
+func NewIndexWriter(w io.Writer, opts ...Option) *IndexWriter {
+	var c writerConfig
+	for _, o := range opts {
+		o(&c)
+	}
+	if c.gzip {
+		gz := gzip.NewWriter(w)
+		return &IndexWriter{w: gz, closer: gz}
+	}
+	return &IndexWriter{w: w}
+}
+
+func (iw *IndexWriter) open() error {
+	if iw.opened {
+		return nil
+	}
+	iw.opened = true
+	_, err := io.WriteString(iw.w, xmlHeader+`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+"\n")
+	return err
+}
+
+// Add writes a single <sitemap> element.
+func (iw *IndexWriter) Add(sm SiteMap) error {
+	if iw.closed {
+		return fmt.Errorf("gositemap: Add called on a closed IndexWriter")
+	}
+	if err := iw.open(); err != nil {
+		return err
+	}
+
+	buf, err := xml.Marshal(sm)
+	if err != nil {
+		return err
+	}
+	if _, err := iw.w.Write(buf); err != nil {
+		return err
+	}
+	_, err = io.WriteString(iw.w, "\n")
+	return err
+}
+
+// Close writes the closing </sitemapindex> tag and, for a gzip
+// IndexWriter, flushes and closes the underlying gzip stream.
+func (iw *IndexWriter) Close() error {
+	if iw.closed {
+		return nil
+	}
+	iw.closed = true
+	if err := iw.open(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(iw.w, "</sitemapindex>\n"); err != nil {
+		return err
+	}
+	if iw.closer != nil {
+		return iw.closer.Close()
+	}
+	return nil
+}
+
+// FileFactory creates the writer and public location (the value to record
+// as <loc> in the sitemap index) for the n-th (0-based) file in a rotated
+// set of sitemaps.
+type FileFactory func(n int) (w io.WriteCloser, loc string, err error)
+
+// SetWriter writes a set of <urlset> files, automatically rotating into a
+// new file obtained from its FileFactory whenever the sitemaps.org
+// per-file limits would otherwise be exceeded, and builds the SiteMaps
+// index covering every file written.
+type SetWriter struct {
+	factory FileFactory
+	opts    []Option
+	cur     *Writer
+	curFile io.WriteCloser
+	maps    []SiteMap
+}
+
+// NewSetWriter returns a SetWriter that obtains each underlying file from
+// factory, passing opts (e.g. WithGzip) through to every Writer it creates.
+func NewSetWriter(factory FileFactory, opts ...Option) *SetWriter {
+	return &SetWriter{factory: factory, opts: opts}
+}
+
+func (sw *SetWriter) rotate() error {
+	if sw.cur != nil {
+		if err := sw.cur.Close(); err != nil {
+			return err
+		}
+		if err := sw.curFile.Close(); err != nil {
+			return err
+		}
+	}
+	f, loc, err := sw.factory(len(sw.maps))
+	if err != nil {
+		return err
+	}
+	sw.curFile = f
+	sw.cur = NewWriter(f, sw.opts...)
+	sw.maps = append(sw.maps, SiteMap{Loc: loc})
+	return nil
+}
+
+// Add writes u to the current file, transparently rotating into a new one
+// whenever the per-file URL count or byte size limit would be exceeded.
+func (sw *SetWriter) Add(u URL) error {
+	if sw.cur == nil {
+		if err := sw.rotate(); err != nil {
+			return err
+		}
+	}
+	if err := sw.cur.Add(u); err != nil {
+		if err := sw.rotate(); err != nil {
+			return err
+		}
+		return sw.cur.Add(u)
+	}
+	return nil
+}
+
+// Index closes the current file and returns the SiteMaps index covering
+// every file written so far. Call it once after the last Add.
+func (sw *SetWriter) Index() (SiteMaps, error) {
+	if sw.cur != nil {
+		if err := sw.cur.Close(); err != nil {
+			return SiteMaps{}, err
+		}
+		if err := sw.curFile.Close(); err != nil {
+			return SiteMaps{}, err
+		}
+		sw.cur = nil
+	}
+	return SiteMaps{Maps: sw.maps}, nil
+}
+
+// Marshal renders the URLSet as a <urlset> XML document, buffering the
+// entire output in memory. It is a convenience wrapper around Writer for
+// callers whose URLs already fit in memory; for large feeds use Writer
+// directly so memory use stays O(1).
+func (s URLSet) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, u := range s.Urls {
+		if err := w.Add(u); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Marshal renders the SiteMaps as a <sitemapindex> XML document, buffering
+// the entire output in memory.
+func (s SiteMaps) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	w := NewIndexWriter(&buf)
+	for _, m := range s.Maps {
+		if err := w.Add(m); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,160 @@
+package gositemap_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	gositemap "github.com/tmeire/go-sitemap"
+)
+
+func TestWriterRoundtrip(t *testing.T) {
+	urls := []gositemap.URL{
+		{Loc: "https://example.com/", Changefreq: gositemap.DAILY},
+		{Loc: "https://example.com/a?b=1&c=2", Changefreq: gositemap.WEEKLY},
+		{Loc: "https://example.com/minimal"},
+	}
+
+	var buf bytes.Buffer
+	w := gositemap.NewWriter(&buf)
+	for _, u := range urls {
+		assert.NoError(t, w.Add(u))
+	}
+	assert.NoError(t, w.Close())
+
+	assert.NotContains(t, buf.String(), "<lastmod>")
+	assert.NotContains(t, buf.String(), "<changefreq>unknown</changefreq>")
+
+	sm, err := gositemap.ParseReaderNative(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Len(t, sm.URLs, 3)
+	assert.Equal(t, "https://example.com/", sm.URLs[0].Loc)
+	assert.Equal(t, "https://example.com/a?b=1&c=2", sm.URLs[1].Loc)
+	assert.Equal(t, gositemap.WEEKLY, sm.URLs[1].Changefreq)
+	assert.Equal(t, "https://example.com/minimal", sm.URLs[2].Loc)
+	assert.Equal(t, gositemap.UKNOWN, sm.URLs[2].Changefreq)
+	assert.True(t, sm.URLs[2].Lastmod.IsZero())
+}
+
+func TestWriterMarshalsZonedLastmodAsUTC(t *testing.T) {
+	loc := time.FixedZone("CEST", 2*60*60)
+	in := time.Date(2024, 2, 23, 8, 20, 45, 0, loc)
+
+	var buf bytes.Buffer
+	w := gositemap.NewWriter(&buf)
+	assert.NoError(t, w.Add(gositemap.URL{
+		Loc:     "https://example.com/",
+		Lastmod: gositemap.TimeISO3339{Time: in},
+	}))
+	assert.NoError(t, w.Close())
+
+	assert.Contains(t, buf.String(), "<lastmod>2024-02-23T06:20:45Z</lastmod>")
+
+	sm, err := gositemap.ParseReaderNative(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.True(t, in.Equal(sm.URLs[0].Lastmod.Time))
+}
+
+func TestWriterEscapesLoc(t *testing.T) {
+	var buf bytes.Buffer
+	w := gositemap.NewWriter(&buf)
+	assert.NoError(t, w.Add(gositemap.URL{Loc: "https://example.com/?a=1&b=2"}))
+	assert.NoError(t, w.Close())
+
+	assert.Contains(t, buf.String(), "&amp;b=2")
+	assert.NotContains(t, buf.String(), "&b=2")
+}
+
+func TestWriterEnforcesURLLimit(t *testing.T) {
+	var buf bytes.Buffer
+	w := gositemap.NewWriter(&buf)
+
+	for i := 0; i < gositemap.MaxURLsPerFile; i++ {
+		assert.NoError(t, w.Add(gositemap.URL{Loc: "https://example.com/"}))
+	}
+	assert.Error(t, w.Add(gositemap.URL{Loc: "https://example.com/one-too-many"}))
+}
+
+func TestWriterGzip(t *testing.T) {
+	var buf bytes.Buffer
+	w := gositemap.NewWriter(&buf, gositemap.WithGzip())
+	assert.NoError(t, w.Add(gositemap.URL{Loc: "https://example.com/"}))
+	assert.NoError(t, w.Close())
+
+	gz, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	defer gz.Close()
+
+	content, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "<urlset")
+}
+
+func TestIndexWriterRoundtrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := gositemap.NewIndexWriter(&buf)
+	assert.NoError(t, w.Add(gositemap.SiteMap{Loc: "https://example.com/sitemap-1.xml"}))
+	assert.NoError(t, w.Add(gositemap.SiteMap{Loc: "https://example.com/sitemap-2.xml"}))
+	assert.NoError(t, w.Close())
+
+	sm, err := gositemap.ParseReaderNative(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Len(t, sm.Maps, 2)
+	assert.Equal(t, "https://example.com/sitemap-1.xml", sm.Maps[0].Loc)
+}
+
+func TestSetWriterRotates(t *testing.T) {
+	files := map[int]*bytes.Buffer{}
+
+	sw := gositemap.NewSetWriter(func(n int) (io.WriteCloser, string, error) {
+		buf := &bytes.Buffer{}
+		files[n] = buf
+		return nopCloser{buf}, fmt.Sprintf("https://example.com/sitemap-%d.xml", n), nil
+	})
+
+	for i := 0; i < gositemap.MaxURLsPerFile+1; i++ {
+		assert.NoError(t, sw.Add(gositemap.URL{Loc: "https://example.com/"}))
+	}
+
+	idx, err := sw.Index()
+	assert.NoError(t, err)
+	assert.Len(t, idx.Maps, 2)
+	assert.Len(t, files, 2)
+
+	first, err := gositemap.ParseReaderNative(bytes.NewReader(files[0].Bytes()))
+	assert.NoError(t, err)
+	assert.Len(t, first.URLs, gositemap.MaxURLsPerFile)
+
+	second, err := gositemap.ParseReaderNative(bytes.NewReader(files[1].Bytes()))
+	assert.NoError(t, err)
+	assert.Len(t, second.URLs, 1)
+}
+
+func TestURLSetMarshal(t *testing.T) {
+	s := gositemap.URLSet{Urls: []gositemap.URL{{Loc: "https://example.com/"}}}
+	b, err := s.Marshal()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "<urlset")
+	assert.Contains(t, string(b), "https://example.com/")
+	assert.NotContains(t, string(b), "<lastmod>")
+	assert.NotContains(t, string(b), "<changefreq>")
+}
+
+func TestSiteMapsMarshal(t *testing.T) {
+	s := gositemap.SiteMaps{Maps: []gositemap.SiteMap{{Loc: "https://example.com/sitemap.xml"}}}
+	b, err := s.Marshal()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "<sitemapindex")
+	assert.Contains(t, string(b), "https://example.com/sitemap.xml")
+	assert.NotContains(t, string(b), "<lastmod>")
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }